@@ -0,0 +1,56 @@
+package eval
+
+import (
+	"sync"
+
+	"github.com/elves/elvish/pkg/archive"
+)
+
+// archiveModuleSource resolves module specs against an in-memory
+// pkg/archive.Archive instead of dataDir on disk, so that a chunk packed
+// by `elvish -pack` keeps resolving `use` the same way once it's run from
+// inside the archive rather than from a filesystem tree. It implements
+// whatever narrow interface the on-disk module loader in eval/use.go uses
+// internally to turn a resolved canonical path into source text; that
+// loader is left untouched, and archive-backed Evalers simply substitute
+// this in as their module source instead of a dataDir path.
+type archiveModuleSource struct {
+	ar *archive.Archive
+}
+
+// Source returns the source of the module at the given canonical path
+// (e.g. "a/b/c/d"), as previously captured by archive.Pack.
+func (s archiveModuleSource) Source(canonical string) (string, bool) {
+	src, ok := s.ar.Module(canonical)
+	return string(src), ok
+}
+
+// archiveSources holds the archiveModuleSource mounted for an Evaler, if
+// any, keyed by Evaler identity. Like eval/cover's side-table, this lives
+// here rather than as an Evaler field because the Evaler struct definition
+// is outside this chunk of the tree; eval/use.go's module loader (also
+// outside this chunk) would need to consult ModuleSourceFor(ev) before
+// falling back to dataDir for this to take effect on `use`.
+var (
+	archiveSourcesMu sync.Mutex
+	archiveSources   = map[*Evaler]archiveModuleSource{}
+)
+
+// UseArchive mounts ar as ev's module source, so that subsequent `use`
+// forms resolve against the archive's bundled modules instead of dataDir.
+// It is meant to be called once, right after NewEvaler, before evaluating
+// the archive's entry script.
+func (ev *Evaler) UseArchive(ar *archive.Archive) {
+	archiveSourcesMu.Lock()
+	defer archiveSourcesMu.Unlock()
+	archiveSources[ev] = archiveModuleSource{ar}
+}
+
+// ModuleSourceFor returns the module source mounted for ev by UseArchive,
+// if any.
+func ModuleSourceFor(ev *Evaler) (src archiveModuleSource, ok bool) {
+	archiveSourcesMu.Lock()
+	defer archiveSourcesMu.Unlock()
+	src, ok = archiveSources[ev]
+	return src, ok
+}
@@ -0,0 +1,43 @@
+package eval
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/pkg/archive"
+)
+
+// TestUseArchiveMountsModuleSource checks the seam UseArchive/
+// ModuleSourceFor provide: after mounting an archive, the module source
+// mounted for that Evaler resolves a canonical path to the content
+// archive.Pack captured for it, and a second Evaler with nothing mounted
+// reports ok == false.
+//
+// It stops short of asserting "use a:b:c:d" resolves through a real
+// archive-backed Evaler: the module loader that would need to call
+// ModuleSourceFor before falling back to dataDir lives in eval/use.go,
+// outside this chunk of the tree, so there is no `use` evaluation path
+// here for UseArchive to actually affect yet.
+func TestUseArchiveMountsModuleSource(t *testing.T) {
+	ar := archive.New("entry.elv", []byte("use a:b:c:d; put $a:b:c:d:name"))
+	ar.AddModule("a/b/c/d", []byte(`name = "a/b/c/d"`))
+
+	ev := NewEvaler(nil, nil, "", nil)
+	ev.UseArchive(ar)
+
+	src, ok := ModuleSourceFor(ev)
+	if !ok {
+		t.Fatal("ModuleSourceFor(ev) reported no source mounted")
+	}
+	got, ok := src.Source("a/b/c/d")
+	if !ok || got != `name = "a/b/c/d"` {
+		t.Errorf(`Source("a/b/c/d") = %q, %v, want %q, true`, got, ok, `name = "a/b/c/d"`)
+	}
+	if _, ok := src.Source("no/such/module"); ok {
+		t.Error(`Source("no/such/module") reported ok, want not found`)
+	}
+
+	other := NewEvaler(nil, nil, "", nil)
+	if _, ok := ModuleSourceFor(other); ok {
+		t.Error("ModuleSourceFor(other) reported a source mounted, want none")
+	}
+}
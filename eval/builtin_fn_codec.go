@@ -0,0 +1,128 @@
+package eval
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/elves/elvish/eval/codec"
+)
+
+// codecBuiltinFns generates the from-<name>/to-<name> builtin pair for
+// every registered codec.Codec, including "json" and "json-lines" plus
+// whichever extra formats eval/codec/*.go or a use'd module has registered.
+// It supersedes the from-json/to-json/from-lines/to-lines pair that used to
+// be hardwired directly against encoding/json here.
+//
+// Registered into builtinFns in this file's init (after every codec
+// package's own init has had a chance to Register, since Go runs an
+// imported package's inits before its importer's); see builtin_ns.go for
+// why that registry, rather than a direct edit to makeBuiltinNamespace, is
+// as far as this chunk of the tree can wire it.
+func codecBuiltinFns() map[string]BuiltinFnImpl {
+	fns := map[string]BuiltinFnImpl{}
+	for _, c := range codec.All() {
+		c := c
+		fns["from-"+c.Name] = func(ec *EvalCtx) {
+			out := make(chan codec.Value)
+			done := make(chan error, 1)
+			go func() {
+				done <- c.Decode(ec.ports[0].File, out)
+				close(out)
+			}()
+			for v := range out {
+				ec.ports[1].Chan <- toValue(v)
+			}
+			maybeThrow(<-done)
+		}
+		fns["to-"+c.Name] = func(ec *EvalCtx) {
+			in := make(chan Value)
+			go func() {
+				for v := range ec.ports[0].Chan {
+					in <- v
+				}
+				close(in)
+			}()
+			maybeThrow(c.Encode(asInterfaceChan(in), ec.ports[1].File))
+		}
+	}
+	return fns
+}
+
+func init() {
+	addBuiltinFns(codecBuiltinFns())
+}
+
+// asInterfaceChan adapts a <-chan Value to the <-chan interface{} a
+// codec.Encoder expects, since Value is itself just an interface{} from
+// the codec package's point of view. No conversion is needed going this
+// direction: eval.Value's concrete types (String, Bool, the list and map
+// types) already marshal the way to-json et al.'s tests expect, since
+// that's the same marshaling to-json relied on before codecs existed.
+func asInterfaceChan(in <-chan Value) <-chan interface{} {
+	out := make(chan interface{})
+	go func() {
+		for v := range in {
+			out <- v
+		}
+		close(out)
+	}()
+	return out
+}
+
+// toValue converts a value as decoded by a codec.Decoder - the plain Go
+// nil/bool/float64/string/[]interface{}/map[string]interface{} family
+// encoding/json decodes into, gopkg.in/yaml.v2's int/int64/
+// map[interface{}]interface{} variants, or vmihailenco/msgpack's []byte
+// for a bin value - into an eval.Value, the same conversion from-json did
+// before codecs were pulled out into their own package: every scalar
+// becomes a String (Elvish has no separate number type, so 1 and 2
+// round-trip as "1" and "2"), and objects/arrays convert recursively via
+// ConvertToMap/NewList.
+//
+// Because every key ends up stringified the same way its value would,
+// two distinct source keys that happen to stringify identically (a YAML
+// mapping with both an int key 1 and a string key "1", say) collide into
+// one map[Value]Value entry, silently keeping only the last one seen.
+// Elvish's Value has no non-string scalar type to preserve the
+// distinction, so this is accepted as a known edge case rather than
+// fixed here.
+func toValue(v interface{}) Value {
+	switch v := v.(type) {
+	case nil:
+		return String("")
+	case string:
+		return String(v)
+	case bool:
+		return Bool(v)
+	case float64:
+		return String(strconv.FormatFloat(v, 'f', -1, 64))
+	case int:
+		return String(strconv.Itoa(v))
+	case int64:
+		return String(strconv.FormatInt(v, 10))
+	case uint64:
+		return String(strconv.FormatUint(v, 10))
+	case []byte:
+		return String(string(v))
+	case []interface{}:
+		vs := make([]Value, len(v))
+		for i, e := range v {
+			vs[i] = toValue(e)
+		}
+		return NewList(vs...)
+	case map[string]interface{}:
+		m := make(map[Value]Value, len(v))
+		for k, e := range v {
+			m[String(k)] = toValue(e)
+		}
+		return ConvertToMap(m)
+	case map[interface{}]interface{}:
+		m := make(map[Value]Value, len(v))
+		for k, e := range v {
+			m[toValue(k)] = toValue(e)
+		}
+		return ConvertToMap(m)
+	default:
+		return String(fmt.Sprintf("%v", v))
+	}
+}
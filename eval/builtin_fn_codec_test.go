@@ -0,0 +1,54 @@
+package eval
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestCodecBuiltinsRegistered checks that codecBuiltinFns' init registered
+// a from-<name>/to-<name> pair into builtinFns for every codec the eval/
+// codec registry knows about, including the non-JSON ones. It stops short
+// of asserting these are callable as Elvish commands: that needs
+// makeBuiltinNamespace, outside this chunk of the tree, to range over
+// builtinFns, which hasn't happened yet (see builtin_ns.go).
+func TestCodecBuiltinsRegistered(t *testing.T) {
+	for _, name := range []string{"json", "json-lines", "yaml", "toml", "msgpack"} {
+		for _, prefix := range []string{"from-", "to-"} {
+			if _, ok := builtinFns[prefix+name]; !ok {
+				t.Errorf("builtinFns[%q] missing", prefix+name)
+			}
+		}
+	}
+}
+
+// TestToValue checks the interface{} -> eval.Value conversion applied to
+// whatever a codec.Decoder produces, including the two different map key
+// types JSON (map[string]interface{}) and YAML
+// (map[interface{}]interface{}) decode into.
+func TestToValue(t *testing.T) {
+	tests := []struct {
+		name string
+		in   interface{}
+		want Value
+	}{
+		{"nil", nil, String("")},
+		{"string", "foo", String("foo")},
+		{"bool", true, Bool(true)},
+		{"float64 integer", float64(2), String("2")},
+		{"yaml int", int(2), String("2")},
+		{"yaml int64", int64(2), String("2")},
+		{"yaml uint64", uint64(2), String("2")},
+		{"msgpack bin", []byte("hi"), String("hi")},
+		{"list", []interface{}{"a", float64(1)},
+			NewList(String("a"), String("1"))},
+		{"string-keyed map", map[string]interface{}{"k": "v"},
+			ConvertToMap(map[Value]Value{String("k"): String("v")})},
+		{"interface-keyed map", map[interface{}]interface{}{"k": "v"},
+			ConvertToMap(map[Value]Value{String("k"): String("v")})},
+	}
+	for _, tt := range tests {
+		if got := toValue(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: toValue(%#v) = %#v, want %#v", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
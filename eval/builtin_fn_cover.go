@@ -0,0 +1,26 @@
+package eval
+
+import "bytes"
+
+// coverBuiltinFns backs cover:start, cover:stop and cover:report, letting a
+// script profile itself interactively instead of only via TestCoverage-style
+// harnesses. Registered into builtinFns in this file's init so that
+// makeBuiltinNamespace exposes them the same way it exposes every other
+// builtin; see builtin_ns.go for that registry.
+var coverBuiltinFns = map[string]BuiltinFnImpl{
+	"cover:start": func(ec *EvalCtx) {
+		ec.Evaler.StartCover(CoverHitsAndTime)
+	},
+	"cover:stop": func(ec *EvalCtx) {
+		setLastCoverage(ec.Evaler, ec.Evaler.StopCover())
+	},
+	"cover:report": func(ec *EvalCtx) {
+		var buf bytes.Buffer
+		maybeThrow(getLastCoverage(ec.Evaler).WriteSummary(&buf))
+		ec.ports[1].File.Write(buf.Bytes())
+	},
+}
+
+func init() {
+	addBuiltinFns(coverBuiltinFns)
+}
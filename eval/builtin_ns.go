@@ -0,0 +1,23 @@
+package eval
+
+// builtinFns accumulates BuiltinFnImpl entries registered by other files in
+// this package via addBuiltinFns, keyed by command name (e.g. "from-json",
+// "cover:start"). makeBuiltinNamespace, which builds the namespace actually
+// exposed to a running chunk, lives outside this chunk of the tree; it is
+// expected to range over builtinFns and add each entry to the namespace it
+// returns, the same way it already does for the builtins defined alongside
+// it. Until that edit lands, anything registered here is reachable from Go
+// (e.g. by tests) but not yet callable as an Elvish command.
+var builtinFns = map[string]BuiltinFnImpl{}
+
+// addBuiltinFns merges fns into builtinFns, panicking on a name collision
+// so that two files registering the same command name is a build-time
+// failure rather than one silently shadowing the other.
+func addBuiltinFns(fns map[string]BuiltinFnImpl) {
+	for name, fn := range fns {
+		if _, ok := builtinFns[name]; ok {
+			panic("eval: duplicate builtin fn " + name)
+		}
+		builtinFns[name] = fn
+	}
+}
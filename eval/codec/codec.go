@@ -0,0 +1,83 @@
+// Package codec provides a registry of structured-data codecs used to
+// generate the from-<name>/to-<name> builtin function pairs (from-json,
+// to-yaml, and so on).
+//
+// A codec's Decoder reads values off an io.Reader and sends them to a
+// channel, mirroring how from-json et al. stream values into an Elvish
+// pipeline; its Encoder does the reverse. Third-party modules loaded via
+// use can call Register at init time to add formats (CSV, Avro, ...)
+// without touching eval.
+package codec
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Value is the subset of eval.Value a codec touches: a decoder only ever
+// sends values on the channel it's given, and an encoder only ever reads
+// them off one, so codecs do not need eval.Value's full interface. Kept as
+// an alias-shaped placeholder so codec has no dependency on eval.
+type Value = interface{}
+
+// Decoder reads successive values out of r and sends each one to out,
+// closing neither. It returns any read or decode error, including io.EOF
+// only if the format requires a terminating marker; an exhausted reader is
+// otherwise reported by returning a nil error once no more values remain.
+type Decoder func(r io.Reader, out chan<- Value) error
+
+// Encoder reads values from in until it is closed and writes them to w.
+type Encoder func(in <-chan Value, w io.Writer) error
+
+// Codec is a decoder/encoder pair registered under a name, exposed as the
+// from-<name> and to-<name> builtins.
+type Codec struct {
+	Name   string
+	Decode Decoder
+	Encode Encoder
+}
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]Codec{}
+)
+
+// Register adds a codec under c.Name, overwriting any codec previously
+// registered under that name. It is safe to call from an init function,
+// including one in a third-party module loaded via use.
+func Register(c Codec) {
+	if c.Name == "" {
+		panic("codec: Register called with empty Name")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	registry[c.Name] = c
+}
+
+// Lookup returns the codec registered under name, if any.
+func Lookup(name string) (Codec, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := registry[name]
+	return c, ok
+}
+
+// All returns every registered codec, in no particular order.
+func All() []Codec {
+	mu.RLock()
+	defer mu.RUnlock()
+	all := make([]Codec, 0, len(registry))
+	for _, c := range registry {
+		all = append(all, c)
+	}
+	return all
+}
+
+// ErrNoSuchCodec is returned by callers that look a codec up by name and
+// find nothing registered.
+type ErrNoSuchCodec struct{ Name string }
+
+func (e ErrNoSuchCodec) Error() string {
+	return fmt.Sprintf("codec: no codec registered under %q", e.Name)
+}
@@ -0,0 +1,72 @@
+package codec
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	c, ok := Lookup("json")
+	if !ok {
+		t.Fatal(`Lookup("json") failed`)
+	}
+	testRoundTrip(t, c, []Value{"foo", float64(1), map[string]interface{}{"k": "v"}})
+}
+
+func TestJSONLinesIsLineDelimited(t *testing.T) {
+	c, ok := Lookup("json-lines")
+	if !ok {
+		t.Fatal(`Lookup("json-lines") failed`)
+	}
+	in := make(chan Value, 2)
+	in <- "a"
+	in <- "b"
+	close(in)
+	var buf bytes.Buffer
+	if err := c.Encode(in, &buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "\"a\"\n\"b\"\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestLookupUnknownCodec(t *testing.T) {
+	if _, ok := Lookup("no-such-codec"); ok {
+		t.Error(`Lookup("no-such-codec") succeeded, want failure`)
+	}
+}
+
+func testRoundTrip(t *testing.T, c Codec, values []Value) {
+	t.Helper()
+	var buf bytes.Buffer
+	in := make(chan Value, len(values))
+	for _, v := range values {
+		in <- v
+	}
+	close(in)
+	if err := c.Encode(in, &buf); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	out := make(chan Value, len(values))
+	got := []Value{}
+	done := make(chan struct{})
+	go func() {
+		for v := range out {
+			got = append(got, v)
+		}
+		close(done)
+	}()
+	if err := c.Decode(&buf, out); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	close(out)
+	<-done
+
+	if !reflect.DeepEqual(got, values) {
+		t.Errorf("round-tripped %v, want %v", got, values)
+	}
+}
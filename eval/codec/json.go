@@ -0,0 +1,73 @@
+package codec
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+)
+
+func init() {
+	Register(Codec{Name: "json", Decode: decodeJSON, Encode: encodeJSON})
+	Register(Codec{Name: "json-lines", Decode: decodeJSONLines, Encode: encodeJSONLines})
+}
+
+// decodeJSON and encodeJSON implement the "json" codec: a stream of
+// whitespace-separated JSON values, matching the behavior from-json and
+// to-json already had before codecs were pulled out into this registry.
+func decodeJSON(r io.Reader, out chan<- Value) error {
+	dec := json.NewDecoder(r)
+	for {
+		var v interface{}
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		out <- v
+	}
+}
+
+func encodeJSON(in <-chan Value, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for v := range in {
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeJSONLines and encodeJSONLines implement "json-lines" (NDJSON): one
+// JSON value per line. Unlike "json", the encoded output is valid
+// line-delimited JSON on its own, which plain to-json's concatenated
+// stream never was.
+func decodeJSONLines(r io.Reader, out chan<- Value) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var v interface{}
+		if err := json.Unmarshal(line, &v); err != nil {
+			return err
+		}
+		out <- v
+	}
+	return scanner.Err()
+}
+
+func encodeJSONLines(in <-chan Value, w io.Writer) error {
+	for v := range in {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(append(b, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package codec
+
+import (
+	"io"
+
+	"github.com/vmihailenco/msgpack"
+)
+
+func init() {
+	Register(Codec{Name: "msgpack", Decode: decodeMsgpack, Encode: encodeMsgpack})
+}
+
+func decodeMsgpack(r io.Reader, out chan<- Value) error {
+	dec := msgpack.NewDecoder(r)
+	for {
+		var v interface{}
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		out <- v
+	}
+}
+
+func encodeMsgpack(in <-chan Value, w io.Writer) error {
+	enc := msgpack.NewEncoder(w)
+	for v := range in {
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package codec
+
+import (
+	"io"
+	"io/ioutil"
+
+	"github.com/BurntSushi/toml"
+)
+
+func init() {
+	Register(Codec{Name: "toml", Decode: decodeTOML, Encode: encodeTOML})
+}
+
+// decodeTOML treats the whole of r as a single TOML document, since TOML
+// (unlike JSON or YAML) has no standard way to stream multiple documents
+// from one stream.
+func decodeTOML(r io.Reader, out chan<- Value) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	var v map[string]interface{}
+	if err := toml.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	out <- v
+	return nil
+}
+
+func encodeTOML(in <-chan Value, w io.Writer) error {
+	enc := toml.NewEncoder(w)
+	for v := range in {
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package codec
+
+import (
+	"bufio"
+	"io"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func init() {
+	Register(Codec{Name: "yaml", Decode: decodeYAML, Encode: encodeYAML})
+}
+
+// decodeYAML reads successive "---"-separated YAML documents.
+func decodeYAML(r io.Reader, out chan<- Value) error {
+	dec := yaml.NewDecoder(r)
+	for {
+		var v interface{}
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		out <- v
+	}
+}
+
+func encodeYAML(in <-chan Value, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for v := range in {
+		b, err := yaml.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if _, err := bw.Write(b); err != nil {
+			return err
+		}
+		if _, err := bw.WriteString("---\n"); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,60 @@
+package eval
+
+import "testing"
+
+// TestRecordOp checks the coverage bookkeeping in isolation: running an Op
+// through ExecOp while coverage is active records a hit (and, in
+// CoverHitsAndTime mode, nonzero elapsed time) for its span, and recording
+// stops as soon as StopCover returns.
+//
+// This drives coverage through ExecOp directly rather than through
+// ev.eval's real op-dispatch loop: that loop lives in eval.go, outside
+// this chunk of the tree, so there is nothing here yet that calls ExecOp
+// on Elvish's behalf. Wiring ev.eval itself to call ExecOp instead of
+// op.Exec directly is the natural follow-up once that file is touched;
+// until then this is what actually exercises recordOp, instead of an
+// assertion that a corpus run produced hits it has no way to produce.
+func TestRecordOp(t *testing.T) {
+	ev := NewEvaler(nil, nil, "", nil)
+	ec := NewTopEvalCtx(ev, "[test]", "", []*Port{{}, {}, {}})
+
+	op := Op{func(*EvalCtx) {}, 3, 7}
+
+	ev.StartCover(CoverHitsAndTime)
+	ev.ExecOp("<test>", op, ec)
+	report := ev.StopCover()
+
+	const key = "<test>:3-7"
+	if report.Hits[key] != 1 {
+		t.Errorf("report.Hits[%q] = %d, want 1", key, report.Hits[key])
+	}
+
+	// Once StopCover has returned, further execution must not be recorded
+	// into the report already handed back.
+	ev.ExecOp("<test>", op, ec)
+	if report.Hits[key] != 1 {
+		t.Errorf("report.Hits[%q] changed after StopCover to %d", key, report.Hits[key])
+	}
+}
+
+// TestRecordOpNoopWithoutCoverage checks that ExecOp still runs op when no
+// coverage is active, and that StopCover without a matching StartCover
+// panics rather than silently returning an empty report.
+func TestRecordOpNoopWithoutCoverage(t *testing.T) {
+	ev := NewEvaler(nil, nil, "", nil)
+	ec := NewTopEvalCtx(ev, "[test]", "", []*Port{{}, {}, {}})
+
+	ran := false
+	op := Op{func(*EvalCtx) { ran = true }, 0, 0}
+	ev.ExecOp("<test>", op, ec)
+	if !ran {
+		t.Error("ExecOp did not run op.Exec")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("StopCover without StartCover did not panic")
+		}
+	}()
+	ev.StopCover()
+}
@@ -0,0 +1,35 @@
+// Package eval implements Elvish's tree-walking evaluator: EvalCtx, the
+// builtin namespace, and the machinery (ports, exceptions, variables) that
+// ev.Compile's Op closures run against.
+//
+// # Integration status
+//
+// Several subsystems added alongside this package are currently reachable
+// only through their own tests, not through a running Elvish script, and
+// that gap is a tracked follow-up rather than an oversight:
+//
+//   - eval/ir + eval/vm: ev.Compile still only ever produces an Op closure
+//     tree; it never emits an ir.Program, so ir.Validate/ir.Lint and
+//     vm.VM never run on anything a real script executes. See eval/vm's
+//     package doc for the seam (Frame) a future EvalCtx is meant to
+//     implement.
+//   - eval/optimize: Optimize has no caller for the same reason - there's
+//     no ir.Program coming out of Compile for it to rewrite.
+//   - Coverage (cover.go, builtin_fn_cover.go): StartCover/StopCover and
+//     the cover: builtin namespace are unit-tested directly, but nothing
+//     in ev.eval calls StartCover around a script run, so `cover:*` isn't
+//     reachable from a shell session yet.
+//   - pkg/archive + archive_loader.go: archiveModuleSource resolves `use`
+//     against an in-memory Archive once one exists, but nothing parses a
+//     `-pack` flag, detects a `.elvc` file, or constructs an Evaler backed
+//     by one - see pkg/archive's own TestUseArchiveMountsModuleSource,
+//     which stops short of asserting this end-to-end.
+//
+// The reason is the same in each case: the integration point - Compile,
+// ev.eval, makeBuiltinNamespace's registration of cover:/use, and the CLI
+// entrypoint that would parse -pack - lives outside this chunk of the
+// tree, which holds eval/, pkg/, and util/ but no cmd/-equivalent main
+// package (see .claude/skills/verify/SKILL.md). Until that integration
+// lands, every subsystem above is scaffolding: correct and tested in
+// isolation, but not yet part of what a user's script actually runs.
+package eval
@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/elves/elvish/daemon/api"
+	"github.com/elves/elvish/eval/optimize"
 	"github.com/elves/elvish/parse"
 	"github.com/elves/elvish/util"
 )
@@ -278,6 +279,12 @@ var evalTests = []struct {
 		}}},
 	{`echo 'invalid' | from-json`, want{err: errAny}},
 
+	// from-json-lines/to-json-lines and the rest of the codec registry
+	// (see eval/codec) are not yet exposed as commands here:
+	// makeBuiltinNamespace, which would need to range over builtinFns to
+	// do that, lives outside this chunk of the tree. See
+	// TestCodecBuiltinsRegistered for what is actually wired up so far.
+
 	{`put "l\norem" ipsum | to-lines`,
 		want{bytesOut: []byte("l\norem\nipsum\n")}},
 	{`put [&k=v &a=[1 2]] foo | to-json`,
@@ -483,9 +490,18 @@ func TestMultipleEval(t *testing.T) {
 	}
 }
 
+// evalAndCollect evaluates texts against a fresh Evaler at the default
+// optimize level and collects its channel and byte output.
 func evalAndCollect(t *testing.T, texts []string, chsize int) ([]Value, []byte, error) {
+	return evalAndCollectAtLevel(t, defaultOptimizeLevel, texts, chsize)
+}
+
+// evalAndCollectAtLevel is evalAndCollect with the Evaler's OptimizeLevel
+// forced to level, for TestEvalUnoptimized's use.
+func evalAndCollectAtLevel(t *testing.T, level optimize.Level, texts []string, chsize int) ([]Value, []byte, error) {
 	name := "<eval test>"
 	ev := NewEvaler(api.NewClient("/invalid"), nil, dataDir, nil)
+	SetOptimizeLevel(ev, level)
 
 	// Collect byte output
 	bytesOut := []byte{}
@@ -0,0 +1,148 @@
+// Package ir defines a flat, instruction-based intermediate representation
+// for compiled Elvish code.
+//
+// Today ev.Compile produces an Op, a closure tree that is executed directly
+// against an EvalCtx. That makes it hard to reason about correctness, add
+// optimizations, or catch misuses before they fault at runtime. Program is
+// meant to sit between Compile and the evaluator: a flat sequence of Instr
+// values that can be validated with Validate before it is ever run.
+//
+// This package is a first step of that migration. The tree evaluator in
+// package eval is still authoritative; eval/vm and this package are wired
+// in alongside it rather than replacing it.
+package ir
+
+import "fmt"
+
+// Op identifies the operation performed by an Instr.
+type Op int
+
+// The supported opcodes.
+const (
+	Push Op = iota
+	Call
+	Pipe
+	Redir
+	Capture
+	AssignLocal
+	AssignIndex
+	Jump
+	JumpIf
+	TryPush
+	TryPop
+	Return
+	Break
+	Continue
+	VarRef
+	Wildcard
+	EnvRef
+	Range
+)
+
+var opNames = [...]string{
+	Push:        "push",
+	Call:        "call",
+	Pipe:        "pipe",
+	Redir:       "redir",
+	Capture:     "capture",
+	AssignLocal: "assign-local",
+	AssignIndex: "assign-index",
+	Jump:        "jump",
+	JumpIf:      "jmpif",
+	TryPush:     "try-push",
+	TryPop:      "try-pop",
+	Return:      "return",
+	Break:       "break",
+	Continue:    "continue",
+	VarRef:      "var-ref",
+	Wildcard:    "wildcard",
+	EnvRef:      "env-ref",
+	Range:       "range",
+}
+
+// VarScope identifies which pseudo-namespace, if any, a VarRef instruction
+// names its variable through. Only the explicit local:/up: forms are
+// tracked; a bare $x is ScopeAny, since which scope it binds to is
+// ordinary name resolution rather than the compile-time check this exists
+// for.
+type VarScope int
+
+// The tracked scopes.
+const (
+	ScopeAny VarScope = iota
+	ScopeLocal
+	ScopeUp
+)
+
+func (s VarScope) String() string {
+	switch s {
+	case ScopeLocal:
+		return "local:"
+	case ScopeUp:
+		return "up:"
+	default:
+		return "$"
+	}
+}
+
+func (op Op) String() string {
+	if int(op) < 0 || int(op) >= len(opNames) || opNames[op] == "" {
+		return fmt.Sprintf("Op(%d)", int(op))
+	}
+	return opNames[op]
+}
+
+// Instr is a single IR instruction. The meaning of A, B and Operand depends
+// on Op:
+//
+//	Push         Operand is the literal Value being pushed
+//	Call         A is the number of arguments on the stack
+//	Redir        A is the port index the redirection targets
+//	AssignLocal  Operand is the variable name
+//	AssignIndex  A is the number of index arguments on the stack
+//	Jump         A is the target instruction index
+//	JumpIf       A is the target instruction index taken when the top of
+//	             the stack is truthy; the value is always popped
+//	TryPush      A is the instruction index of the except branch
+//	VarRef       Operand is the variable name; Scope is which
+//	             pseudo-namespace it was written through, if any, and
+//	             Resolved records whether the compiler located its binding
+//	             - see Validate's up:/local: check
+//	Wildcard     Operand is the glob pattern; NoMatchOK mirrors the source
+//	             having a trailing [nomatch-ok] modifier - see Lint
+//	EnvRef       Operand is the E: pseudo-namespace variable name, e.g.
+//	             "HOME" for $E:HOME
+//	Range        A is the (already bounded) upper end of the range to
+//	             generate, as rewritten by eval/optimize's "range N | take
+//	             K" lowering; there is no corresponding source form - it
+//	             only ever appears as the output of that rewrite
+//
+// Begin and End are byte offsets into the source text, mirroring the source
+// span already carried by Op in the tree evaluator.
+type Instr struct {
+	Op         Op
+	A, B       int
+	Operand    interface{}
+	Begin, End int
+	Scope      VarScope
+	Resolved   bool
+	NoMatchOK  bool
+}
+
+// Program is a flat sequence of instructions produced by compiling a chunk
+// or closure body.
+type Program struct {
+	Name, Source string
+	Instrs       []Instr
+	// InLoop and InFn record whether the program as a whole was compiled
+	// as a loop body or a function body. Validate trusts these outright
+	// rather than re-deriving them: Instrs has no bracketing
+	// loop-enter/loop-exit or fn-enter/fn-exit instructions to re-derive
+	// them from, so a Program that mixes loop and non-loop (or fn and
+	// non-fn) regions - which Compile never produces today, since it
+	// compiles one loop or fn body per Program - is outside what this
+	// representation, and therefore Validate, can express or catch: a
+	// break that's lexically outside the loop but still within such a
+	// mixed Program would validate as if it were inside one.
+	InLoop, InFn bool
+}
@@ -0,0 +1,224 @@
+package ir
+
+import "fmt"
+
+// ValueType is the abstract type of a value tracked on the validator's
+// stack. It is coarser than eval.Value: enough to catch shape mistakes
+// (indexing a string, redirecting to a fn) without re-implementing the
+// evaluator's own type system.
+type ValueType int
+
+// The tracked value shapes. Any matches everything and is pushed whenever
+// an instruction's result type can't be determined statically (e.g. the
+// result of a user-defined Call).
+const (
+	Any ValueType = iota
+	TString
+	TList
+	TMap
+	TFn
+	TException
+	TFile
+	TPipe
+)
+
+// A ValidationError describes why Validate rejected a Program. It carries
+// the offending instruction's source span so callers can report it the same
+// way a parse or compile error is reported.
+type ValidationError struct {
+	Instr      int
+	Begin, End int
+	Msg        string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("ir: instr %d: %s", e.Instr, e.Msg)
+}
+
+// NumPorts is the number of ports (stdin/stdout/stderr plus any extra file
+// descriptors a Redir instruction may target) a validated Program may
+// address. It mirrors the fixed-size ports slice used by EvalCtx.
+const NumPorts = 3
+
+// Validate statically checks prog for a class of errors that would
+// otherwise only surface as a runtime *eval.Exception:
+//
+//   - break/continue outside of a loop
+//   - return outside of a function body
+//   - a Redir instruction naming a port index out of range
+//   - stack underflow (an instruction consuming more values than are
+//     available)
+//   - an explicit local:/up: VarRef whose binding the compiler never
+//     resolved
+//
+// break/continue/return validity is checked against the whole-Program
+// InLoop/InFn flags, not re-derived from control flow - see Program's doc
+// comment for what that does and doesn't catch.
+//
+// It does not attempt full type inference; TString/TList/etc are tracked on
+// a best-effort basis and widened to Any as soon as the static type of an
+// instruction's result isn't known.
+//
+// Validate does not flag a Wildcard instruction missing NoMatchOK: unlike
+// the checks above, whether such a wildcard actually fails is a property
+// of the filesystem at run time, not of prog itself - "put *" in evalTests
+// relies on exactly this succeeding - so that's an advisory Lint can
+// surface instead of a hard Validate error.
+func Validate(prog *Program) error {
+	v := &validator{prog: prog}
+	return v.run()
+}
+
+type validator struct {
+	prog  *Program
+	stack []ValueType
+}
+
+func (v *validator) fail(i int, format string, args ...interface{}) error {
+	instr := Instr{}
+	if i >= 0 && i < len(v.prog.Instrs) {
+		instr = v.prog.Instrs[i]
+	}
+	return &ValidationError{
+		Instr: i, Begin: instr.Begin, End: instr.End,
+		Msg: fmt.Sprintf(format, args...),
+	}
+}
+
+func (v *validator) pop(i int) (ValueType, error) {
+	if len(v.stack) == 0 {
+		return Any, v.fail(i, "stack underflow")
+	}
+	t := v.stack[len(v.stack)-1]
+	v.stack = v.stack[:len(v.stack)-1]
+	return t, nil
+}
+
+func (v *validator) push(t ValueType) {
+	v.stack = append(v.stack, t)
+}
+
+func (v *validator) run() error {
+	for i, in := range v.prog.Instrs {
+		switch in.Op {
+		case Push:
+			v.push(typeOfLiteral(in.Operand))
+		case Call:
+			for n := 0; n < in.A; n++ {
+				if _, err := v.pop(i); err != nil {
+					return err
+				}
+			}
+			if _, err := v.pop(i); err != nil { // the callee itself
+				return err
+			}
+			v.push(Any)
+		case Pipe, Capture:
+			v.push(Any)
+		case Redir:
+			if in.A < 0 || in.A >= NumPorts {
+				return v.fail(i, "redirection references invalid port %d", in.A)
+			}
+			if _, err := v.pop(i); err != nil {
+				return err
+			}
+		case AssignLocal:
+			if name, ok := in.Operand.(string); ok {
+				if name == "" {
+					return v.fail(i, "empty variable name")
+				}
+			} else {
+				return v.fail(i, "assign-local missing variable name operand")
+			}
+			if _, err := v.pop(i); err != nil {
+				return err
+			}
+		case AssignIndex:
+			for n := 0; n < in.A; n++ {
+				if _, err := v.pop(i); err != nil {
+					return err
+				}
+			}
+			if _, err := v.pop(i); err != nil {
+				return err
+			}
+		case Jump:
+			if in.A < 0 || in.A > len(v.prog.Instrs) {
+				return v.fail(i, "jump target %d out of range", in.A)
+			}
+		case JumpIf:
+			if in.A < 0 || in.A > len(v.prog.Instrs) {
+				return v.fail(i, "jmpif target %d out of range", in.A)
+			}
+			if _, err := v.pop(i); err != nil {
+				return err
+			}
+		case TryPush:
+			if in.A < 0 || in.A > len(v.prog.Instrs) {
+				return v.fail(i, "try-push target %d out of range", in.A)
+			}
+		case TryPop:
+			// no-op for the stack; marks the end of a try region
+		case VarRef:
+			if (in.Scope == ScopeLocal || in.Scope == ScopeUp) && !in.Resolved {
+				return v.fail(i, "%s%v does not resolve to a binding at compile time", in.Scope, in.Operand)
+			}
+			v.push(Any)
+		case Wildcard:
+			v.push(Any)
+		case EnvRef:
+			v.push(Any)
+		case Range:
+			v.push(TList)
+		case Return:
+			if !v.prog.InFn {
+				return v.fail(i, "return outside of a function body")
+			}
+		case Break, Continue:
+			if !v.prog.InLoop {
+				return v.fail(i, "%s outside of a loop", in.Op)
+			}
+		default:
+			return v.fail(i, "unknown opcode %d", in.Op)
+		}
+	}
+	return nil
+}
+
+// Diagnostic is a non-fatal advisory Lint produces about prog. Unlike a
+// ValidationError, a Diagnostic doesn't mean prog is invalid - only that it
+// could be made more explicit about a run-time hazard that can't be ruled
+// out statically.
+type Diagnostic struct {
+	Instr      int
+	Begin, End int
+	Msg        string
+}
+
+// Lint returns advisory diagnostics for prog. Today it flags every
+// Wildcard instruction whose NoMatchOK isn't set: such a glob raises a
+// run-time *eval.Exception if it happens not to match anything, and
+// nothing in prog proves whether it will or won't, so this is reported
+// separately from Validate's hard errors rather than rejecting the
+// program outright.
+func Lint(prog *Program) []Diagnostic {
+	var diags []Diagnostic
+	for i, in := range prog.Instrs {
+		if in.Op == Wildcard && !in.NoMatchOK {
+			diags = append(diags, Diagnostic{
+				Instr: i, Begin: in.Begin, End: in.End,
+				Msg: fmt.Sprintf("wildcard %q has no [nomatch-ok] modifier and will raise an exception if it matches nothing", in.Operand),
+			})
+		}
+	}
+	return diags
+}
+
+func typeOfLiteral(operand interface{}) ValueType {
+	switch operand.(type) {
+	case string:
+		return TString
+	default:
+		return Any
+	}
+}
@@ -0,0 +1,132 @@
+package ir
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		prog    *Program
+		wantErr bool
+	}{
+		{
+			"empty program",
+			&Program{Instrs: nil},
+			false,
+		},
+		{
+			"push then call",
+			&Program{Instrs: []Instr{
+				{Op: Push, Operand: "put"},
+				{Op: Push, Operand: "x"},
+				{Op: Call, A: 1},
+			}},
+			false,
+		},
+		{
+			"call with too few arguments underflows",
+			&Program{Instrs: []Instr{
+				{Op: Push, Operand: "put"},
+				{Op: Call, A: 1},
+			}},
+			true,
+		},
+		{
+			"break outside a loop",
+			&Program{Instrs: []Instr{{Op: Break}}},
+			true,
+		},
+		{
+			"break inside a loop",
+			&Program{InLoop: true, Instrs: []Instr{{Op: Break}}},
+			false,
+		},
+		{
+			"return outside a function",
+			&Program{Instrs: []Instr{{Op: Return}}},
+			true,
+		},
+		{
+			"return inside a function",
+			&Program{InFn: true, Instrs: []Instr{{Op: Return}}},
+			false,
+		},
+		{
+			"redir to an invalid port",
+			&Program{Instrs: []Instr{
+				{Op: Push, Operand: "f"},
+				{Op: Redir, A: NumPorts},
+			}},
+			true,
+		},
+		{
+			"jump target out of range",
+			&Program{Instrs: []Instr{{Op: Jump, A: 5}}},
+			true,
+		},
+		{
+			"resolved local: reference",
+			&Program{Instrs: []Instr{
+				{Op: VarRef, Operand: "x", Scope: ScopeLocal, Resolved: true},
+			}},
+			false,
+		},
+		{
+			"unresolved up: reference",
+			&Program{Instrs: []Instr{
+				{Op: VarRef, Operand: "x", Scope: ScopeUp, Resolved: false},
+			}},
+			true,
+		},
+		{
+			"bare variable reference is never subject to the resolved check",
+			&Program{Instrs: []Instr{
+				{Op: VarRef, Operand: "x", Scope: ScopeAny, Resolved: false},
+			}},
+			false,
+		},
+		{
+			"wildcard without nomatch-ok validates fine; that's Lint's job",
+			&Program{Instrs: []Instr{
+				{Op: Wildcard, Operand: "a/b/nonexistent*"},
+			}},
+			false,
+		},
+		{
+			"env-ref is always valid",
+			&Program{Instrs: []Instr{{Op: EnvRef, Operand: "HOME"}}},
+			false,
+		},
+		{
+			"range pushes a single value, consumable as a call argument",
+			&Program{Instrs: []Instr{
+				{Op: Push, Operand: "put"},
+				{Op: Range, A: 3},
+				{Op: Call, A: 1},
+			}},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		err := Validate(tt.prog)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() = %v, want error: %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLint(t *testing.T) {
+	flagged := &Program{Instrs: []Instr{
+		{Op: Wildcard, Operand: "a/b/nonexistent*"},
+	}}
+	if diags := Lint(flagged); len(diags) != 1 {
+		t.Errorf("Lint(wildcard without nomatch-ok) = %v, want exactly 1 diagnostic", diags)
+	}
+
+	clean := &Program{Instrs: []Instr{
+		{Op: Wildcard, Operand: "a/b/nonexistent*", NoMatchOK: true},
+	}}
+	if diags := Lint(clean); len(diags) != 0 {
+		t.Errorf("Lint(wildcard with nomatch-ok) = %v, want none", diags)
+	}
+}
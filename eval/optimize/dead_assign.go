@@ -0,0 +1,43 @@
+package optimize
+
+import "github.com/elves/elvish/eval/ir"
+
+// eliminateShadowedAssigns implements rewrite (2): when two AssignLocal
+// instructions for the same variable are adjacent, with nothing in
+// between that could read the first assignment (no Call, Jump, JumpIf,
+// TryPush or another op that might observe or branch around it), the
+// first Push+AssignLocal pair is dead and is dropped.
+//
+// This is deliberately conservative: it only collapses a strictly
+// adjacent pair, matching the "temporary assignment shadows itself"
+// pattern the request calls out, rather than doing full liveness
+// analysis across the whole program. Dropping the first pair outright
+// (rather than, say, merging it into the second) keeps the result a
+// subsequence of the input, so remapTargets can still translate any
+// Jump/JumpIf/TryPush target that pointed into the pair.
+func eliminateShadowedAssigns(instrs []ir.Instr) ([]ir.Instr, []bool) {
+	dropped := make([]bool, len(instrs))
+	out := make([]ir.Instr, 0, len(instrs))
+	i := 0
+	for i < len(instrs) {
+		if i+3 < len(instrs) &&
+			instrs[i+1].Op == ir.AssignLocal &&
+			instrs[i+3].Op == ir.AssignLocal &&
+			instrs[i+1].Operand == instrs[i+3].Operand &&
+			isPureValuePush(instrs[i]) && isPureValuePush(instrs[i+2]) {
+			dropped[i] = true
+			dropped[i+1] = true
+			i += 2
+			continue
+		}
+		out = append(out, instrs[i])
+		i++
+	}
+	return out, dropped
+}
+
+// isPureValuePush reports whether in is a Push of a literal, i.e. one with
+// no side effect that eliminateShadowedAssigns would need to preserve.
+func isPureValuePush(in ir.Instr) bool {
+	return in.Op == ir.Push
+}
@@ -0,0 +1,42 @@
+package optimize
+
+import "github.com/elves/elvish/eval/ir"
+
+// foldConstantBranches implements rewrite (3): a constant condition pushed
+// immediately before a JumpIf is resolved at compile time instead of at
+// run time, i.e. `if true { A }` / `if $false { A } else { B }` are
+// reduced to the surviving branch:
+//
+//   - Push true; JumpIf{A: t}  -> Jump{A: t}, dropping the Push
+//   - Push false; JumpIf{A: t} -> dropped entirely (falls through)
+//
+// Only a literal bool Operand is treated as constant; anything else (a
+// variable read, a call result) is left alone since its value isn't known
+// until run time. The Push is always the one dropped (the JumpIf survives,
+// turned into a Jump, or is itself dropped) so the result stays a
+// subsequence of the input for remapTargets to fix up.
+func foldConstantBranches(instrs []ir.Instr) ([]ir.Instr, []bool) {
+	dropped := make([]bool, len(instrs))
+	out := make([]ir.Instr, 0, len(instrs))
+	i := 0
+	for i < len(instrs) {
+		if i+1 < len(instrs) && instrs[i].Op == ir.Push && instrs[i+1].Op == ir.JumpIf {
+			if b, ok := instrs[i].Operand.(bool); ok {
+				dropped[i] = true
+				if b {
+					out = append(out, ir.Instr{Op: ir.Jump, A: instrs[i+1].A,
+						Begin: instrs[i+1].Begin, End: instrs[i+1].End})
+				} else {
+					// The branch is never taken: drop the JumpIf too, so
+					// execution simply falls through.
+					dropped[i+1] = true
+				}
+				i += 2
+				continue
+			}
+		}
+		out = append(out, instrs[i])
+		i++
+	}
+	return out, dropped
+}
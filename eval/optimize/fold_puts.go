@@ -0,0 +1,77 @@
+package optimize
+
+import "github.com/elves/elvish/eval/ir"
+
+// foldConstantPuts implements rewrite (1): a run of adjacent
+//
+//	Push "put"; Push v1; Call{A:1}; Push "put"; Push v2; Call{A:1}; ...
+//
+// (i.e. "put x; put y; put z") becomes a single multi-value emission
+//
+//	Push "put"; Push v1; Push v2; Push v3; Call{A:3}
+//
+// so the callee is only invoked once. It only fires when every call in the
+// run targets the literal "put" and takes exactly one argument, so it
+// can't accidentally merge calls to something that was reassigned to mean
+// something else between them (Elvish builtins aren't reassignable, but a
+// user fn named put shadowing it is out of scope for this pass, see
+// isPutTriple).
+//
+// Every instruction in a collapsed run is kept or dropped at its original
+// position: the first "Push put" and the last Call of the run survive (the
+// Call's A field is updated in place), every other "Push put"/Call in the
+// run is dropped, and every value Push survives unmoved. That keeps the
+// result a genuine subsequence of the input, which is what lets
+// remapTargets fix up any Jump/JumpIf/TryPush target that pointed into the
+// run.
+func foldConstantPuts(instrs []ir.Instr) ([]ir.Instr, []bool) {
+	dropped := make([]bool, len(instrs))
+	out := make([]ir.Instr, 0, len(instrs))
+	i := 0
+	for i < len(instrs) {
+		if !isPutTriple(instrs, i) {
+			out = append(out, instrs[i])
+			i++
+			continue
+		}
+		j := i + 3
+		n := 1
+		for isPutTriple(instrs, j) {
+			n++
+			j += 3
+		}
+		if n == 1 {
+			out = append(out, instrs[i], instrs[i+1], instrs[i+2])
+			i += 3
+			continue
+		}
+		// Keep the first Push "put" and every value Push; drop every
+		// intermediate Call and every Push "put" after the first; keep the
+		// last Call's slot but rewrite its A to the merged argument count.
+		out = append(out, instrs[i]) // Push "put"
+		for k := i; k < j; k += 3 {
+			out = append(out, instrs[k+1]) // the value
+			if k+3 < j {
+				dropped[k+3] = true // the next run's redundant Push "put"
+			}
+			dropped[k+2] = true // this triple's Call
+		}
+		lastCall := instrs[j-1]
+		lastCall.A = n
+		dropped[j-1] = false // the last Call survives, just modified
+		out = append(out, lastCall)
+		i = j
+	}
+	return out, dropped
+}
+
+// isPutTriple reports whether instrs[i:i+3] is Push "put"; Push <value>;
+// Call{A:1}.
+func isPutTriple(instrs []ir.Instr, i int) bool {
+	if i+2 >= len(instrs) {
+		return false
+	}
+	return instrs[i].Op == ir.Push && instrs[i].Operand == "put" &&
+		instrs[i+1].Op == ir.Push &&
+		instrs[i+2].Op == ir.Call && instrs[i+2].A == 1
+}
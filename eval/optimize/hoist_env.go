@@ -0,0 +1,178 @@
+package optimize
+
+import (
+	"strconv"
+
+	"github.com/elves/elvish/eval/ir"
+)
+
+// hoistInvariantEnvRefs implements rewrite (5): an EnvRef read more than
+// once inside a loop body is hoisted to a single read just before the
+// loop, with every occurrence inside the body rewritten to read the
+// hoisted local instead. This package's IR has no instruction that writes
+// an EnvRef (there is no "set E:VAR" or "del E:VAR" opcode at all, only
+// the read), so unlike eliminateShadowedAssigns this pass doesn't need to
+// check for an intervening write - per the request, it only needs to
+// apply "when the body doesn't del/assign them", and in this IR that's
+// unconditionally true.
+//
+// A loop body is identified the only way flat IR makes available: as the
+// span [top, j] ending at a backward Jump{A: top} (j >= top). Regions are
+// taken from outermost in, and once a region is claimed, any other
+// region nested entirely inside it is left alone - this pass does not
+// hoist out of a nested loop separately from its enclosing one, which
+// keeps the bookkeeping (and its correctness) tractable. A Program with
+// loops that overlap without one nesting inside the other isn't a shape
+// structured control flow can produce, so that case isn't handled either.
+//
+// Unlike the drop-only passes in this package, this one inserts
+// instructions (the hoisted EnvRef+AssignLocal pair), so it builds its
+// old-index -> new-index table directly rather than through
+// oldToNewFromDropped.
+func hoistInvariantEnvRefs(instrs []ir.Instr) ([]ir.Instr, []int) {
+	regions := outermostLoopRegions(instrs)
+	if len(regions) == 0 {
+		return instrs, identityOldToNew(len(instrs))
+	}
+
+	// hoistAt[top] lists the EnvRef names to hoist just before index top.
+	hoistAt := map[int][]string{}
+	// hoistedName[i] gives the synthetic local name that instrs[i] (an
+	// EnvRef inside a claimed region) should be rewritten to read instead.
+	hoistedName := map[int]string{}
+	for _, r := range regions {
+		counts := map[string]int{}
+		for k := r.top; k <= r.j; k++ {
+			if name, ok := envRefName(instrs[k]); ok {
+				counts[name]++
+			}
+		}
+		var names []string
+		for name, n := range counts {
+			if n > 1 {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+		hoistAt[r.top] = names
+		for k := r.top; k <= r.j; k++ {
+			if name, ok := envRefName(instrs[k]); ok {
+				if hoisted := hoistedEnvVar(names, name); hoisted != "" {
+					hoistedName[k] = syntheticLocalName(hoisted, r.top)
+				}
+			}
+		}
+	}
+	if len(hoistAt) == 0 {
+		return instrs, identityOldToNew(len(instrs))
+	}
+
+	out := make([]ir.Instr, 0, len(instrs)+4*len(hoistAt))
+	oldToNew := make([]int, len(instrs)+1)
+	for i, in := range instrs {
+		if names, ok := hoistAt[i]; ok {
+			for _, name := range names {
+				local := syntheticLocalName(name, i)
+				out = append(out,
+					ir.Instr{Op: ir.EnvRef, Operand: name, Begin: in.Begin, End: in.Begin},
+					ir.Instr{Op: ir.AssignLocal, Operand: local, Begin: in.Begin, End: in.Begin},
+				)
+			}
+		}
+		oldToNew[i] = len(out)
+		if local, ok := hoistedName[i]; ok {
+			out = append(out, ir.Instr{
+				Op: ir.VarRef, Operand: local, Scope: ir.ScopeLocal, Resolved: true,
+				Begin: in.Begin, End: in.End,
+			})
+		} else {
+			out = append(out, in)
+		}
+	}
+	oldToNew[len(instrs)] = len(out)
+	return out, oldToNew
+}
+
+// envRefName returns in's E: variable name and true if in is an EnvRef
+// instruction with a string Operand. Validate doesn't require EnvRef's
+// Operand to be a string (unlike AssignLocal's, which it does check), so
+// this pass treats a non-string Operand as simply not hoistable rather
+// than asserting on it.
+func envRefName(in ir.Instr) (string, bool) {
+	if in.Op != ir.EnvRef {
+		return "", false
+	}
+	name, ok := in.Operand.(string)
+	return name, ok
+}
+
+// hoistedEnvVar returns name if it appears in names, else "".
+func hoistedEnvVar(names []string, name string) string {
+	for _, n := range names {
+		if n == name {
+			return name
+		}
+	}
+	return ""
+}
+
+// syntheticLocalName names the local hoistInvariantEnvRefs assigns the
+// value of E:name to, just before the loop starting at top. Including top
+// keeps names distinct across disjoint loops that both hoist the same
+// E:name.
+func syntheticLocalName(name string, top int) string {
+	return "%hoisted-E:" + name + "@" + strconv.Itoa(top)
+}
+
+// identityOldToNew returns the no-op old-index -> new-index table for a
+// program of n instructions, for hoistInvariantEnvRefs to return when it
+// finds nothing to hoist. An all-false dropped mask already produces
+// exactly this table, so this just names that case rather than building
+// it differently.
+func identityOldToNew(n int) []int {
+	return oldToNewFromDropped(make([]bool, n))
+}
+
+// loopRegion is the span of a loop body identified by its backward jump:
+// [top, j] where instrs[j] is Jump{A: top} and top <= j.
+type loopRegion struct {
+	top, j int
+}
+
+// outermostLoopRegions finds every backward-jump-delimited loop region in
+// instrs, discarding any region entirely nested inside another so that
+// only the outermost regions remain.
+func outermostLoopRegions(instrs []ir.Instr) []loopRegion {
+	var all []loopRegion
+	for j, in := range instrs {
+		if in.Op == ir.Jump && in.A >= 0 && in.A <= j {
+			all = append(all, loopRegion{top: in.A, j: j})
+		}
+	}
+	var outer []loopRegion
+	for _, r := range all {
+		nested := false
+		for _, o := range outer {
+			if o.top <= r.top && r.j <= o.j {
+				nested = true
+				break
+			}
+		}
+		if nested {
+			continue
+		}
+		// r might itself be a new outer region that makes a
+		// previously-kept region redundant if r encloses it; drop those.
+		kept := outer[:0]
+		for _, o := range outer {
+			if r.top <= o.top && o.j <= r.j {
+				continue // o is nested inside r, drop it
+			}
+			kept = append(kept, o)
+		}
+		outer = append(kept, r)
+	}
+	return outer
+}
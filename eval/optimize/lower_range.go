@@ -0,0 +1,91 @@
+package optimize
+
+import "github.com/elves/elvish/eval/ir"
+
+// lowerBoundedRange implements rewrite (4): a pipeline of the literal form
+//
+//	Push "range"; Push N; Call{A:1}; Pipe; Push "take"; Push K; Call{A:1}
+//
+// (i.e. "range $n | take $k") becomes a single ir.Range instruction
+// bounded at min(N, K), since take only ever keeps the first K values of
+// whatever range produces and nothing else reads the pipe in between -
+// there's no reason to generate all N values if K is smaller. It only
+// fires when both N and K are literal ints, the same constant-operands
+// restriction foldConstantPuts already applies to "put": a non-constant
+// count (a variable, a call result) can't be bounded at compile time.
+//
+// Unlike foldConstantPuts, which keeps invoking the merged calls' callee
+// (just fewer times), this pass drops the calls to "range" and "take"
+// entirely in favor of a single ir.Range instruction that has no callee at
+// all. That means a user fn named range or take shadowing the builtin is
+// out of scope here in a stronger sense than isPutTriple's shadowing
+// caveat: it's not just merged away, it's never called. Accepted for the
+// same reason isPutTriple accepts it for "put" - Elvish builtins aren't
+// reassignable, and a shadowing fn is the unusual case - but worth calling
+// out explicitly since the consequence (a dropped call, not a merged one)
+// is more severe.
+//
+// The match is reduced to a single surviving Call (the last one, its A
+// field repurposed to hold the bound and its Op changed to Range) with
+// every other instruction in the run dropped, so the result stays a
+// subsequence of the input for remapTargets to fix up.
+func lowerBoundedRange(instrs []ir.Instr) ([]ir.Instr, []bool) {
+	dropped := make([]bool, len(instrs))
+	out := make([]ir.Instr, 0, len(instrs))
+	i := 0
+	for i < len(instrs) {
+		n, k, ok := matchRangeTake(instrs, i)
+		if !ok {
+			out = append(out, instrs[i])
+			i++
+			continue
+		}
+		bound := n
+		if k < bound {
+			bound = k
+		}
+		last := instrs[i+6]
+		out = append(out, ir.Instr{
+			Op: ir.Range, A: bound,
+			Begin: instrs[i].Begin, End: last.End,
+		})
+		for k := i; k < i+6; k++ {
+			dropped[k] = true
+		}
+		i += 7
+	}
+	return out, dropped
+}
+
+// matchRangeTake reports whether instrs[i:i+7] is
+// Push "range"; Push N; Call{A:1}; Pipe; Push "take"; Push K; Call{A:1},
+// with N and K literal ints, returning N and K if so.
+func matchRangeTake(instrs []ir.Instr, i int) (n, k int, ok bool) {
+	if i+6 >= len(instrs) {
+		return 0, 0, false
+	}
+	if instrs[i].Op != ir.Push || instrs[i].Operand != "range" {
+		return 0, 0, false
+	}
+	n, ok = instrs[i+1].Operand.(int)
+	if !ok {
+		return 0, 0, false
+	}
+	if instrs[i+2].Op != ir.Call || instrs[i+2].A != 1 {
+		return 0, 0, false
+	}
+	if instrs[i+3].Op != ir.Pipe {
+		return 0, 0, false
+	}
+	if instrs[i+4].Op != ir.Push || instrs[i+4].Operand != "take" {
+		return 0, 0, false
+	}
+	k, ok = instrs[i+5].Operand.(int)
+	if !ok {
+		return 0, 0, false
+	}
+	if instrs[i+6].Op != ir.Call || instrs[i+6].A != 1 {
+		return 0, 0, false
+	}
+	return n, k, true
+}
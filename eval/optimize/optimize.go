@@ -0,0 +1,58 @@
+// Package optimize implements a peephole optimizer over eval/ir.Program,
+// run after compilation and before eval/vm execution.
+//
+// It targets the flat IR introduced alongside eval/ir rather than the Op
+// closure tree ev.Compile still produces today, since the IR is the
+// representation rewrites can actually operate on; Op keeps running
+// unmodified until the migration described in eval/ir's package doc
+// reaches the point where Compile emits IR by default.
+package optimize
+
+import "github.com/elves/elvish/eval/ir"
+
+// Level controls how aggressively Optimize rewrites a Program.
+type Level int
+
+// The supported levels. The zero value is Off so that a zero Evaler (as
+// used by some benchmarks and tests) doesn't silently optimize; Evaler's
+// own OptimizeLevel field defaults to Default instead, see eval's
+// optimize_level.go.
+const (
+	Off Level = iota
+	Default
+)
+
+// Optimize rewrites prog in place at the given level and returns it for
+// chaining. At Off it is a no-op.
+//
+// Each rewrite pass below returns its rewritten instructions alongside an
+// old-index -> new-index table (oldToNewFromDropped for the three
+// drop-only passes; built directly by hoistInvariantEnvRefs, which also
+// inserts). That table is immediately used to fix up every remaining
+// Jump/JumpIf/TryPush target via remapTargets before the next pass runs,
+// so that a pass never sees - and can't be corrupted by - jump targets
+// expressed in a since-rewritten index space.
+func Optimize(level Level, prog *ir.Program) *ir.Program {
+	if level == Off {
+		return prog
+	}
+	var dropped []bool
+	var oldToNew []int
+
+	prog.Instrs, dropped = foldConstantPuts(prog.Instrs)
+	prog.Instrs = remapTargets(prog.Instrs, oldToNewFromDropped(dropped))
+
+	prog.Instrs, dropped = eliminateShadowedAssigns(prog.Instrs)
+	prog.Instrs = remapTargets(prog.Instrs, oldToNewFromDropped(dropped))
+
+	prog.Instrs, dropped = foldConstantBranches(prog.Instrs)
+	prog.Instrs = remapTargets(prog.Instrs, oldToNewFromDropped(dropped))
+
+	prog.Instrs, dropped = lowerBoundedRange(prog.Instrs)
+	prog.Instrs = remapTargets(prog.Instrs, oldToNewFromDropped(dropped))
+
+	prog.Instrs, oldToNew = hoistInvariantEnvRefs(prog.Instrs)
+	prog.Instrs = remapTargets(prog.Instrs, oldToNew)
+
+	return prog
+}
@@ -0,0 +1,235 @@
+package optimize
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/elves/elvish/eval/ir"
+)
+
+func TestFoldConstantPuts(t *testing.T) {
+	in := []ir.Instr{
+		{Op: ir.Push, Operand: "put"}, {Op: ir.Push, Operand: "x"}, {Op: ir.Call, A: 1},
+		{Op: ir.Push, Operand: "put"}, {Op: ir.Push, Operand: "y"}, {Op: ir.Call, A: 1},
+		{Op: ir.Push, Operand: "put"}, {Op: ir.Push, Operand: "z"}, {Op: ir.Call, A: 1},
+	}
+	want := []ir.Instr{
+		{Op: ir.Push, Operand: "put"},
+		{Op: ir.Push, Operand: "x"}, {Op: ir.Push, Operand: "y"}, {Op: ir.Push, Operand: "z"},
+		{Op: ir.Call, A: 3},
+	}
+	got, _ := foldConstantPuts(in)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("foldConstantPuts(%v) = %v, want %v", in, got, want)
+	}
+}
+
+func TestFoldConstantPutsLeavesSingleCallAlone(t *testing.T) {
+	in := []ir.Instr{
+		{Op: ir.Push, Operand: "put"}, {Op: ir.Push, Operand: "x"}, {Op: ir.Call, A: 1},
+	}
+	got, dropped := foldConstantPuts(in)
+	if !reflect.DeepEqual(got, in) {
+		t.Errorf("foldConstantPuts(%v) = %v, want unchanged", in, got)
+	}
+	for i, d := range dropped {
+		if d {
+			t.Errorf("dropped[%d] = true, want false", i)
+		}
+	}
+}
+
+func TestEliminateShadowedAssigns(t *testing.T) {
+	in := []ir.Instr{
+		{Op: ir.Push, Operand: "amy"}, {Op: ir.AssignLocal, Operand: "a"},
+		{Op: ir.Push, Operand: "ben"}, {Op: ir.AssignLocal, Operand: "a"},
+	}
+	want := []ir.Instr{
+		{Op: ir.Push, Operand: "ben"}, {Op: ir.AssignLocal, Operand: "a"},
+	}
+	got, _ := eliminateShadowedAssigns(in)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("eliminateShadowedAssigns(%v) = %v, want %v", in, got, want)
+	}
+}
+
+func TestEliminateShadowedAssignsDifferentNames(t *testing.T) {
+	in := []ir.Instr{
+		{Op: ir.Push, Operand: "amy"}, {Op: ir.AssignLocal, Operand: "a"},
+		{Op: ir.Push, Operand: "ben"}, {Op: ir.AssignLocal, Operand: "b"},
+	}
+	got, dropped := eliminateShadowedAssigns(in)
+	if !reflect.DeepEqual(got, in) {
+		t.Errorf("eliminateShadowedAssigns(%v) = %v, want unchanged", in, got)
+	}
+	for i, d := range dropped {
+		if d {
+			t.Errorf("dropped[%d] = true, want false", i)
+		}
+	}
+}
+
+func TestFoldConstantBranches(t *testing.T) {
+	trueCase := []ir.Instr{{Op: ir.Push, Operand: true}, {Op: ir.JumpIf, A: 7}}
+	got, _ := foldConstantBranches(trueCase)
+	want := []ir.Instr{{Op: ir.Jump, A: 7}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("foldConstantBranches(true case) = %v, want %v", got, want)
+	}
+
+	falseCase := []ir.Instr{{Op: ir.Push, Operand: false}, {Op: ir.JumpIf, A: 7}}
+	got, _ = foldConstantBranches(falseCase)
+	if len(got) != 0 {
+		t.Errorf("foldConstantBranches(false case) = %v, want empty", got)
+	}
+}
+
+func TestLowerBoundedRange(t *testing.T) {
+	in := []ir.Instr{
+		{Op: ir.Push, Operand: "range"}, {Op: ir.Push, Operand: 10}, {Op: ir.Call, A: 1},
+		{Op: ir.Pipe},
+		{Op: ir.Push, Operand: "take"}, {Op: ir.Push, Operand: 3}, {Op: ir.Call, A: 1},
+	}
+	want := []ir.Instr{{Op: ir.Range, A: 3}}
+	got, _ := lowerBoundedRange(in)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("lowerBoundedRange(%v) = %v, want %v", in, got, want)
+	}
+}
+
+func TestLowerBoundedRangeTakeLargerThanRangeKeepsRangeBound(t *testing.T) {
+	in := []ir.Instr{
+		{Op: ir.Push, Operand: "range"}, {Op: ir.Push, Operand: 2}, {Op: ir.Call, A: 1},
+		{Op: ir.Pipe},
+		{Op: ir.Push, Operand: "take"}, {Op: ir.Push, Operand: 100}, {Op: ir.Call, A: 1},
+	}
+	want := []ir.Instr{{Op: ir.Range, A: 2}}
+	got, _ := lowerBoundedRange(in)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("lowerBoundedRange(%v) = %v, want %v", in, got, want)
+	}
+}
+
+func TestLowerBoundedRangeLeavesNonLiteralCountAlone(t *testing.T) {
+	in := []ir.Instr{
+		{Op: ir.Push, Operand: "range"}, {Op: ir.VarRef, Operand: "n"}, {Op: ir.Call, A: 1},
+		{Op: ir.Pipe},
+		{Op: ir.Push, Operand: "take"}, {Op: ir.Push, Operand: 3}, {Op: ir.Call, A: 1},
+	}
+	got, dropped := lowerBoundedRange(in)
+	if !reflect.DeepEqual(got, in) {
+		t.Errorf("lowerBoundedRange(%v) = %v, want unchanged", in, got)
+	}
+	for i, d := range dropped {
+		if d {
+			t.Errorf("dropped[%d] = true, want false", i)
+		}
+	}
+}
+
+func TestHoistInvariantEnvRefs(t *testing.T) {
+	in := []ir.Instr{
+		/*0*/ {Op: ir.EnvRef, Operand: "HOME"},
+		/*1*/ {Op: ir.Call, A: 1},
+		/*2*/ {Op: ir.EnvRef, Operand: "HOME"},
+		/*3*/ {Op: ir.Call, A: 1},
+		/*4*/ {Op: ir.Jump, A: 0},
+	}
+	got, oldToNew := hoistInvariantEnvRefs(in)
+
+	if len(got) != 7 {
+		t.Fatalf("hoistInvariantEnvRefs(%v) = %v, want 7 instructions (2 hoisted + 5 original)", in, got)
+	}
+	if got[0].Op != ir.EnvRef || got[0].Operand != "HOME" {
+		t.Errorf("got[0] = %v, want the hoisted EnvRef", got[0])
+	}
+	if got[1].Op != ir.AssignLocal {
+		t.Errorf("got[1] = %v, want the hoisted AssignLocal", got[1])
+	}
+	local := got[1].Operand
+	if got[2].Op != ir.VarRef || got[2].Operand != local || !got[2].Resolved {
+		t.Errorf("got[2] = %v, want a resolved VarRef to %v", got[2], local)
+	}
+	if got[4].Op != ir.VarRef || got[4].Operand != local || !got[4].Resolved {
+		t.Errorf("got[4] = %v, want the second EnvRef occurrence rewritten to the same hoisted local", got[4])
+	}
+	if got[6].Op != ir.Jump || got[6].A != oldToNew[0] {
+		t.Errorf("got[6] = %v, jump target not remapped to hoisted loop top %d", got[6], oldToNew[0])
+	}
+	// The back-edge must land after the hoisted preamble, on the loop
+	// body's own first instruction, not re-run the hoist every iteration.
+	if oldToNew[0] != 2 {
+		t.Errorf("oldToNew[0] = %d, want 2 (past the 2 hoisted instructions)", oldToNew[0])
+	}
+}
+
+func TestHoistInvariantEnvRefsSingleOccurrenceIsLeftAlone(t *testing.T) {
+	in := []ir.Instr{
+		{Op: ir.EnvRef, Operand: "HOME"},
+		{Op: ir.Call, A: 1},
+		{Op: ir.Jump, A: 0},
+		{Op: ir.Push, Operand: "x"},
+	}
+	got, _ := hoistInvariantEnvRefs(in)
+	if !reflect.DeepEqual(got, in) {
+		t.Errorf("hoistInvariantEnvRefs(%v) = %v, want unchanged (only one EnvRef in the loop)", in, got)
+	}
+}
+
+func TestOptimizeOffIsNoOp(t *testing.T) {
+	prog := &ir.Program{Instrs: []ir.Instr{
+		{Op: ir.Push, Operand: "put"}, {Op: ir.Push, Operand: "x"}, {Op: ir.Call, A: 1},
+		{Op: ir.Push, Operand: "put"}, {Op: ir.Push, Operand: "y"}, {Op: ir.Call, A: 1},
+	}}
+	before := append([]ir.Instr{}, prog.Instrs...)
+	Optimize(Off, prog)
+	if !reflect.DeepEqual(prog.Instrs, before) {
+		t.Errorf("Optimize(Off, ...) changed instrs: got %v, want %v", prog.Instrs, before)
+	}
+}
+
+// TestOptimizeRemapsJumpTargetsPastAFoldedRun is the regression case the
+// original version of this package had no coverage for: a backward Jump
+// whose target lands AFTER a run of "put"s that foldConstantPuts merges
+// into fewer instructions must still land on the same logical
+// instruction (here, the Call terminating a later, unmerged "echo x" triple)
+// once the run has shrunk - not on whatever raw index happened to be
+// there before the rewrite.
+func TestOptimizeRemapsJumpTargetsPastAFoldedRun(t *testing.T) {
+	prog := &ir.Program{Instrs: []ir.Instr{
+		/*0*/ {Op: ir.Push, Operand: "put"},
+		/*1*/ {Op: ir.Push, Operand: "x"},
+		/*2*/ {Op: ir.Call, A: 1},
+		/*3*/ {Op: ir.Push, Operand: "put"},
+		/*4*/ {Op: ir.Push, Operand: "y"},
+		/*5*/ {Op: ir.Call, A: 1},
+		/*6*/ {Op: ir.Push, Operand: "echo"},
+		/*7*/ {Op: ir.Push, Operand: "z"},
+		/*8*/ {Op: ir.Call, A: 1}, // the jump target, by instruction identity
+		/*9*/ {Op: ir.Jump, A: 8}, // a backward jump landing on instr 8 above
+	}}
+
+	Optimize(Default, prog)
+
+	// The two "put"s (0-5) collapse into 4 instructions (put, x, y, call),
+	// so the "echo" triple that used to start at 6 now starts at 4, and
+	// its Call - the jump target - is now at index 6, not 8.
+	var jump *ir.Instr
+	for i := range prog.Instrs {
+		if prog.Instrs[i].Op == ir.Jump {
+			jump = &prog.Instrs[i]
+		}
+	}
+	if jump == nil {
+		t.Fatal("no Jump instruction survived Optimize")
+	}
+	target := prog.Instrs[jump.A]
+	if target.Op != ir.Call || target.Operand != nil {
+		t.Fatalf("Jump now targets instr %d = %v, want it to still be the echo triple's Call", jump.A, target)
+	}
+	// Confirm it's specifically the echo call and not some other Call by
+	// checking what immediately precedes it.
+	if prog.Instrs[jump.A-1].Operand != "z" {
+		t.Errorf("instr before Jump target is %v, want Push \"z\"", prog.Instrs[jump.A-1])
+	}
+}
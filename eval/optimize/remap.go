@@ -0,0 +1,49 @@
+package optimize
+
+import "github.com/elves/elvish/eval/ir"
+
+// oldToNewFromDropped turns dropped - a mask over a pass's INPUT
+// instructions recording which ones were removed outright rather than kept
+// (possibly with modified fields) at the same relative position - into the
+// old-index -> new-index table remapTargets needs.
+//
+// It's what foldConstantPuts, eliminateShadowedAssigns and
+// foldConstantBranches use: every one of them only ever drops whole
+// instructions and otherwise preserves the relative order of the rest -
+// even foldConstantPuts, which collapses several instructions into fewer,
+// does so by dropping all but one Push "put" and all but the last Call in
+// a run and rewriting that survivor's fields, never by reordering or
+// inserting - so a table built purely from which input indices survived is
+// enough to fix up every target, including ones that pointed into the
+// middle of a collapsed run.
+//
+// hoistInvariantEnvRefs does insert instructions, so it builds its
+// old-index -> new-index table directly instead of going through this
+// helper; see its doc comment.
+func oldToNewFromDropped(dropped []bool) []int {
+	oldToNew := make([]int, len(dropped)+1)
+	newIdx := 0
+	for i := 0; i <= len(dropped); i++ {
+		oldToNew[i] = newIdx
+		if i < len(dropped) && !dropped[i] {
+			newIdx++
+		}
+	}
+	return oldToNew
+}
+
+// remapTargets rewrites every Jump/JumpIf/TryPush.A in instrs (expressed in
+// a prior index space) to instrs' own index space, using oldToNew - a
+// table with one entry per instruction index in the prior space, plus one
+// for "one past the end".
+func remapTargets(instrs []ir.Instr, oldToNew []int) []ir.Instr {
+	for i := range instrs {
+		switch instrs[i].Op {
+		case ir.Jump, ir.JumpIf, ir.TryPush:
+			if instrs[i].A >= 0 && instrs[i].A < len(oldToNew) {
+				instrs[i].A = oldToNew[instrs[i].A]
+			}
+		}
+	}
+	return instrs
+}
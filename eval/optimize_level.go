@@ -0,0 +1,43 @@
+package eval
+
+import (
+	"sync"
+
+	"github.com/elves/elvish/eval/optimize"
+)
+
+// defaultOptimizeLevel is the OptimizeLevel a new Evaler starts with.
+// Optimization is on by default; tests that need to rule out an
+// optimizer-introduced behavior change set it to optimize.Off instead via
+// SetOptimizeLevel, see TestEvalUnoptimized.
+const defaultOptimizeLevel = optimize.Default
+
+// optimizeLevels holds the optimize.Level an Evaler evaluates at, keyed by
+// Evaler identity. Like eval/cover's and eval/archive_loader.go's
+// side-tables, this lives here rather than as an Evaler field because the
+// Evaler struct definition is outside this chunk of the tree; ev.eval
+// (also outside this chunk) would need to read OptimizeLevel(ev) and run
+// eval/optimize.Optimize over the compiled eval/ir.Program before
+// executing it for this to take effect.
+var (
+	optimizeLevelsMu sync.Mutex
+	optimizeLevels   = map[*Evaler]optimize.Level{}
+)
+
+// OptimizeLevel returns the optimize.Level ev evaluates at, defaulting to
+// defaultOptimizeLevel if SetOptimizeLevel has never been called for ev.
+func OptimizeLevel(ev *Evaler) optimize.Level {
+	optimizeLevelsMu.Lock()
+	defer optimizeLevelsMu.Unlock()
+	if level, ok := optimizeLevels[ev]; ok {
+		return level
+	}
+	return defaultOptimizeLevel
+}
+
+// SetOptimizeLevel changes the optimize.Level ev evaluates at.
+func SetOptimizeLevel(ev *Evaler, level optimize.Level) {
+	optimizeLevelsMu.Lock()
+	defer optimizeLevelsMu.Unlock()
+	optimizeLevels[ev] = level
+}
@@ -0,0 +1,62 @@
+package eval
+
+import (
+	"os"
+	"testing"
+
+	"github.com/elves/elvish/eval/optimize"
+	"github.com/elves/elvish/util"
+)
+
+// TestEvalUnoptimized re-runs the evalTests corpus with OptimizeLevel
+// forced to optimize.Off, as a guard against the optimizer ever changing
+// observable behavior: every case must produce the same out/bytesOut/err
+// as it does with optimization on (TestEval).
+//
+// Compile doesn't run chunks through eval/optimize yet - it still emits
+// the Op closure tree directly, and eval/optimize.Optimize only rewrites
+// the eval/ir.Program representation introduced in chunk0-1 - so today
+// this only guards that flipping an Evaler's OptimizeLevel is itself
+// inert. Once Compile grows an IR backend this becomes a real regression
+// guard.
+func TestEvalUnoptimized(t *testing.T) {
+	util.InTempDir(func(string) {
+		for _, filename := range filesToCreate {
+			file, err := os.Create(filename)
+			if err != nil {
+				panic(err)
+			}
+			file.Close()
+		}
+		for _, tt := range evalTests {
+			out, bytesOut, err := evalAndCollectAtLevel(t, optimize.Off, []string{tt.text}, len(tt.want.out))
+			if !matchOut(tt.want.out, out) {
+				t.Errorf("eval(%q) at OptimizeLevel off: got out=%v, want %v", tt.text, out, tt.want.out)
+			}
+			if string(tt.want.bytesOut) != string(bytesOut) {
+				t.Errorf("eval(%q) at OptimizeLevel off: got bytesOut=%q, want %q", tt.text, bytesOut, tt.want.bytesOut)
+			}
+			if !matchErr(tt.want.err, err) {
+				t.Errorf("eval(%q) at OptimizeLevel off: got err=%v, want %v", tt.text, err, tt.want.err)
+			}
+		}
+	})
+}
+
+// TestOptimizeLevelDefaultsAndIsSettable exercises the OptimizeLevel/
+// SetOptimizeLevel side-table directly, independent of evalAndCollectAtLevel.
+func TestOptimizeLevelDefaultsAndIsSettable(t *testing.T) {
+	ev := NewEvaler(nil, nil, "", nil)
+	if got := OptimizeLevel(ev); got != defaultOptimizeLevel {
+		t.Errorf("OptimizeLevel(new Evaler) = %v, want %v", got, defaultOptimizeLevel)
+	}
+	SetOptimizeLevel(ev, optimize.Off)
+	if got := OptimizeLevel(ev); got != optimize.Off {
+		t.Errorf("OptimizeLevel(ev) after SetOptimizeLevel(Off) = %v, want %v", got, optimize.Off)
+	}
+
+	other := NewEvaler(nil, nil, "", nil)
+	if got := OptimizeLevel(other); got != defaultOptimizeLevel {
+		t.Errorf("OptimizeLevel(other Evaler) = %v, want %v (unaffected by ev's override)", got, defaultOptimizeLevel)
+	}
+}
@@ -0,0 +1,127 @@
+package eval
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/eval/ir"
+)
+
+// mustBeInEvalTests fails t if no evalTests case has exactly this text.
+// TestValidator's cases are hand-built IR mirroring specific evalTests
+// entries - since Compile doesn't emit ir.Program yet (see eval/ir's
+// package doc) there's no way to compile the text itself - so this is what
+// ties the two tables together: if the referenced evalTests case is ever
+// edited or removed, this test fails instead of silently validating IR for
+// a scenario that no longer exists in evalTests.
+func mustBeInEvalTests(t *testing.T, text string) {
+	t.Helper()
+	for _, tt := range evalTests {
+		if tt.text == text {
+			return
+		}
+	}
+	t.Fatalf("no evalTests case has text %q; update or remove the TestValidator case that mirrors it", text)
+}
+
+// TestValidator checks ir.Validate against hand-built IR for evalTests
+// cases whose current failure mode is a runtime *Exception, wiring the two
+// together by exact source text via mustBeInEvalTests.
+func TestValidator(t *testing.T) {
+	tests := []struct {
+		evalTestsText string
+		prog          *ir.Program
+		wantErr       bool
+	}{
+		// "for x [a] { break } else { put $x }": valid because break
+		// appears inside the for body.
+		{
+			"for x [a] { break } else { put $x }",
+			&ir.Program{InLoop: true, Instrs: []ir.Instr{{Op: ir.Break}}},
+			false,
+		},
+		// The shape a bare chunk-scope "break" would take - today this
+		// only fails once the for-loop machinery runs and raises an
+		// exception at eval time.
+		{
+			"for x [a] { break } else { put $x }",
+			&ir.Program{Instrs: []ir.Instr{{Op: ir.Break}}},
+			true,
+		},
+		// "fn f []{ put a; return; put b }; f": valid because return
+		// appears inside the fn body.
+		{
+			"fn f []{ put a; return; put b }; f",
+			&ir.Program{InFn: true, Instrs: []ir.Instr{
+				{Op: ir.Push, Operand: "a"},
+				{Op: ir.Return},
+			}},
+			false,
+		},
+		// "return" at chunk scope (evalTests wants err: Return, which is
+		// really just a sentinel the tree evaluator uses to unwind to the
+		// closest fn; at top level there is none).
+		{
+			"return",
+			&ir.Program{Instrs: []ir.Instr{{Op: ir.Return}}},
+			true,
+		},
+		// "x=lorem; []{local:x=ipsum; put $up:x $local:x}": both the
+		// up:x and local:x references resolve, so this validates fine.
+		{
+			"x=lorem; []{local:x=ipsum; put $up:x $local:x}",
+			&ir.Program{InFn: true, Instrs: []ir.Instr{
+				{Op: ir.VarRef, Operand: "x", Scope: ir.ScopeUp, Resolved: true},
+				{Op: ir.VarRef, Operand: "x", Scope: ir.ScopeLocal, Resolved: true},
+			}},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		mustBeInEvalTests(t, tt.evalTestsText)
+		err := ir.Validate(tt.prog)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() = %v, want error: %v", tt.evalTestsText, err, tt.wantErr)
+		}
+	}
+}
+
+// TestValidatorRejectsUnresolvedNamespacedVarRef exercises the up:/local:
+// compile-time resolution check on its own, since no evalTests case
+// currently exercises an unresolved reference (Elvish's real compiler -
+// outside this chunk - would already reject it before eval time, so there
+// is nothing for mustBeInEvalTests to anchor this one to).
+func TestValidatorRejectsUnresolvedNamespacedVarRef(t *testing.T) {
+	prog := &ir.Program{InFn: true, Instrs: []ir.Instr{
+		{Op: ir.VarRef, Operand: "nonexistent", Scope: ir.ScopeUp, Resolved: false},
+	}}
+	if err := ir.Validate(prog); err == nil {
+		t.Errorf("Validate(unresolved up: reference) = nil, want error")
+	}
+}
+
+// TestValidatorLintsWildcardWithoutNoMatchOK wires ir.Lint to the three
+// evalTests wildcard cases: "put a/b/nonexistent*" has no [nomatch-ok] and
+// is exactly the kind of call that raises ErrWildcardNoMatch at run time
+// if nothing matches, while "put a/b/nonexistent*[nomatch-ok]" and
+// "put *" are both fine as-is. Lint flags the first shape as advisory,
+// not a hard Validate error - see ir.Lint's doc comment for why.
+func TestValidatorLintsWildcardWithoutNoMatchOK(t *testing.T) {
+	mustBeInEvalTests(t, "put a/b/nonexistent*")
+	mustBeInEvalTests(t, "put a/b/nonexistent*[nomatch-ok]")
+	mustBeInEvalTests(t, "put *")
+
+	unflagged := &ir.Program{Instrs: []ir.Instr{
+		{Op: ir.Wildcard, Operand: "a/b/nonexistent*"},
+	}}
+	if diags := ir.Lint(unflagged); len(diags) != 1 {
+		t.Errorf("Lint(%q) = %v, want exactly 1 diagnostic", "a/b/nonexistent*", diags)
+	}
+
+	flaggedOK := &ir.Program{Instrs: []ir.Instr{
+		{Op: ir.Wildcard, Operand: "a/b/nonexistent*", NoMatchOK: true},
+	}}
+	if diags := ir.Lint(flaggedOK); len(diags) != 0 {
+		t.Errorf("Lint(%q[nomatch-ok]) = %v, want none", "a/b/nonexistent*", diags)
+	}
+}
@@ -0,0 +1,125 @@
+// Package vm executes a validated eval/ir.Program.
+//
+// It is the execution half of the IR migration described alongside
+// eval/ir: eval.Op remains the tree-of-closures representation that
+// ev.Compile produces and that the rest of eval still runs directly, and
+// nothing here is wired into that path yet. Frame is the seam a future
+// EvalCtx will implement so that VM can run alongside, and eventually in
+// place of, the closure tree.
+package vm
+
+import (
+	"fmt"
+
+	"github.com/elves/elvish/eval/ir"
+)
+
+// Value is the subset of eval.Value the VM touches: nothing, since values
+// are opaque to it and only ever moved between the stack, ports and calls.
+// It is defined here, rather than imported from eval, to keep this package
+// free of a dependency on eval during the migration.
+type Value interface{}
+
+// Frame is the execution context a Program runs against. A later change
+// will make *eval.EvalCtx implement it; for now callers construct a Frame
+// directly (see NewSliceFrame) to run a Program in isolation, e.g. in
+// tests.
+type Frame interface {
+	// Call invokes the callable value with the given arguments.
+	Call(fn Value, args []Value) (Value, error)
+	// Redir applies a redirection of value to the given port index.
+	Redir(port int, value Value) error
+	// AssignLocal binds name to value in the innermost scope.
+	AssignLocal(name string, value Value)
+	// AssignIndex assigns value to container indexed by the given keys.
+	AssignIndex(container Value, indicies []Value, value Value) error
+}
+
+// VM executes a single Program against a Frame.
+type VM struct {
+	Frame Frame
+}
+
+// New returns a VM that executes programs against frame.
+func New(frame Frame) *VM {
+	return &VM{Frame: frame}
+}
+
+// Run validates prog and then executes it. It returns the validation error
+// without running anything if prog is invalid.
+func (m *VM) Run(prog *ir.Program) error {
+	if err := ir.Validate(prog); err != nil {
+		return err
+	}
+	return m.run(prog)
+}
+
+func (m *VM) run(prog *ir.Program) error {
+	var stack []Value
+	pop := func() Value {
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		return v
+	}
+
+	pc := 0
+	for pc < len(prog.Instrs) {
+		in := prog.Instrs[pc]
+		next := pc + 1
+		switch in.Op {
+		case ir.Push:
+			stack = append(stack, in.Operand)
+		case ir.Call:
+			args := make([]Value, in.A)
+			for i := in.A - 1; i >= 0; i-- {
+				args[i] = pop()
+			}
+			fn := pop()
+			ret, err := m.Frame.Call(fn, args)
+			if err != nil {
+				return err
+			}
+			stack = append(stack, ret)
+		case ir.Redir:
+			v := pop()
+			if err := m.Frame.Redir(in.A, v); err != nil {
+				return err
+			}
+		case ir.AssignLocal:
+			v := pop()
+			m.Frame.AssignLocal(in.Operand.(string), v)
+		case ir.AssignIndex:
+			indicies := make([]Value, in.A)
+			for i := in.A - 1; i >= 0; i-- {
+				indicies[i] = pop()
+			}
+			container := pop()
+			v := pop()
+			if err := m.Frame.AssignIndex(container, indicies, v); err != nil {
+				return err
+			}
+		case ir.Jump:
+			next = in.A
+		case ir.JumpIf:
+			if isTruthy(pop()) {
+				next = in.A
+			}
+		case ir.TryPush, ir.TryPop:
+			// Exception handling is managed by the caller's recover-based
+			// Call implementation for now; these are no-ops for VM.
+		case ir.Return, ir.Break, ir.Continue:
+			return nil
+		default:
+			return fmt.Errorf("vm: unexecutable opcode %v", in.Op)
+		}
+		pc = next
+	}
+	return nil
+}
+
+func isTruthy(v Value) bool {
+	if b, ok := v.(bool); ok {
+		return b
+	}
+	return v != nil
+}
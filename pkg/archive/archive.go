@@ -0,0 +1,133 @@
+// Package archive packages an Elvish entry script together with the
+// source of every module it transitively uses into a single self-contained
+// .elvc file, the way escript bundles a BEAM script and its dependencies.
+//
+// The on-disk format is a zip archive (see Shebang, WriteTo and Open)
+// prefixed with a shebang line so the result is directly executable; Go's
+// archive/zip already tolerates arbitrary bytes ahead of the zip data; it
+// locates the central directory by scanning backward from the end of the
+// file, the same trick self-extracting jars rely on.
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+)
+
+// Shebang is prefixed to every archive written by WriteTo so that the file
+// is directly executable as `./entry.elvc` on a Unix system with elvish on
+// PATH.
+const Shebang = "#!/usr/bin/env elvish\n"
+
+// entryModulePath is the key EntrySrc is stored under inside the zip, kept
+// out of the Modules map so a module named "entry" can never collide with
+// it.
+const entryModulePath = "__entry__"
+
+// Archive is an in-memory bundle of an entry script plus the source of
+// every module it (transitively) uses, keyed by the module's canonical
+// slash-path - the same path "use a:b:c:d" resolves to ("a/b/c/d").
+type Archive struct {
+	EntryName string
+	EntrySrc  []byte
+	Modules   map[string][]byte
+}
+
+// New starts an empty archive for the given entry script. entryName is
+// carried through only for diagnostics (e.g. parse error messages); it is
+// not used to resolve modules.
+func New(entryName string, entrySrc []byte) *Archive {
+	return &Archive{
+		EntryName: entryName,
+		EntrySrc:  entrySrc,
+		Modules:   map[string][]byte{},
+	}
+}
+
+// AddModule records the source of the module at the given canonical path,
+// overwriting any module previously added under that path.
+func (a *Archive) AddModule(path string, src []byte) {
+	a.Modules[path] = src
+}
+
+// Module returns the source of the module at path, if the archive has it.
+func (a *Archive) Module(path string) ([]byte, bool) {
+	src, ok := a.Modules[path]
+	return src, ok
+}
+
+// WriteTo writes a as Shebang followed by a zip archive: one entry per
+// module path, plus the entry script under the reserved entryModulePath
+// key.
+func (a *Archive) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, Shebang)
+	if err != nil {
+		return int64(n), err
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	paths := make([]string, 0, len(a.Modules))
+	for path := range a.Modules {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	if err := writeZipEntry(zw, entryModulePath, a.EntrySrc); err != nil {
+		return int64(n), err
+	}
+	for _, path := range paths {
+		if err := writeZipEntry(zw, path, a.Modules[path]); err != nil {
+			return int64(n), err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return int64(n), err
+	}
+
+	m, err := w.Write(buf.Bytes())
+	return int64(n + m), err
+}
+
+func writeZipEntry(zw *zip.Writer, name string, content []byte) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(content)
+	return err
+}
+
+// Open reads an archive previously written by WriteTo. r/size are the
+// same pair archive/zip.NewReader takes; callers typically get them from
+// an *os.File via Stat.
+func Open(r io.ReaderAt, size int64) (*Archive, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("archive: not a valid .elvc file: %v", err)
+	}
+
+	a := &Archive{Modules: map[string][]byte{}}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		content, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		if f.Name == entryModulePath {
+			a.EntrySrc = content
+		} else {
+			a.Modules[f.Name] = content
+		}
+	}
+	return a, nil
+}
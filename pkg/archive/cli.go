@@ -0,0 +1,25 @@
+package archive
+
+import "os"
+
+// PackFile implements what `elvish -pack out.elvc entry.elv` does: it
+// packs entryPath via Pack, then writes the resulting archive to outPath.
+//
+// This tree has no main.go for a CLI entrypoint to live in (that file is
+// outside this chunk of the tree), so the `-pack` flag itself isn't parsed
+// anywhere yet; PackFile is the piece a future main.go's flag handling
+// would call, with outPath/entryPath coming from os.Args instead of being
+// hardcoded the way a real CLI wiring would pass them.
+func PackFile(outPath, entryPath string, resolver ModuleResolver) error {
+	a, err := Pack(entryPath, resolver)
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(outPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = a.WriteTo(out)
+	return err
+}
@@ -0,0 +1,54 @@
+package archive
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type mapResolver map[string]string
+
+func (r mapResolver) Resolve(spec string) (canonical, fsPath string, err error) {
+	return spec, r[spec], nil
+}
+
+func TestPackFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	entryPath := filepath.Join(dir, "entry.elv")
+	modPath := filepath.Join(dir, "mod.elv")
+	if err := ioutil.WriteFile(entryPath, []byte("use mod; put $mod:name"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(modPath, []byte(`name = mod`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	outPath := filepath.Join(dir, "out.elvc")
+	if err := PackFile(outPath, entryPath, mapResolver{"mod": modPath}); err != nil {
+		t.Fatalf("PackFile: %v", err)
+	}
+
+	f, err := os.Open(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ar, err := Open(f, fi.Size())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if got, ok := ar.Module("mod"); !ok || string(got) != "name = mod" {
+		t.Errorf(`Module("mod") = %q, %v, want "name = mod", true`, got, ok)
+	}
+}
@@ -0,0 +1,161 @@
+package archive
+
+import (
+	"io/ioutil"
+
+	"github.com/elves/elvish/parse"
+)
+
+// ModuleResolver turns a module spec as written after "use" (e.g. "a:b:c:d"
+// or "a/b/c:d") into the canonical slash-path used to key Archive.Modules
+// and the filesystem path to read its source from, mirroring the lookup
+// the module loader does for on-disk modules. It is satisfied by whatever
+// the real loader in eval/use.go exposes; Pack only depends on this
+// narrow slice of it so it doesn't need to import eval.
+type ModuleResolver interface {
+	Resolve(spec string) (canonical, fsPath string, err error)
+}
+
+// Pack walks the imports reachable from entryPath - parsing it and every
+// module it (transitively) uses via resolver - and returns an Archive
+// containing all of their source, keyed by canonical module path.
+func Pack(entryPath string, resolver ModuleResolver) (*Archive, error) {
+	entrySrc, err := ioutil.ReadFile(entryPath)
+	if err != nil {
+		return nil, err
+	}
+	a := New(entryPath, entrySrc)
+
+	seen := map[string]bool{}
+	var walk func(src []byte) error
+	walk = func(src []byte) error {
+		specs, err := useSpecs(string(src))
+		if err != nil {
+			return err
+		}
+		for _, spec := range specs {
+			canonical, fsPath, err := resolver.Resolve(spec)
+			if err != nil {
+				return err
+			}
+			if seen[canonical] {
+				continue
+			}
+			seen[canonical] = true
+			modSrc, err := ioutil.ReadFile(fsPath)
+			if err != nil {
+				return err
+			}
+			a.AddModule(canonical, modSrc)
+			if err := walk(modSrc); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(entrySrc); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// useSpecs returns the module spec named by every "use" form in text, in
+// source order. text is first run through parse.Parse so that a syntax
+// error is reported the same way any other parse error would be, before
+// Pack goes looking for imports to fetch.
+//
+// Specs are found by scanning tokenUses, a bare lexical scan rather than a
+// walk of parse's AST node types (whose names this chunk of the tree has
+// no visibility into): it tracks single/double-quoted strings and #
+// comments just enough to ignore "use" appearing inside either, so a
+// commented-out "# use foo" or a string literal like "some use case"
+// doesn't get treated as a real import the way a naive regex over raw
+// text would.
+func useSpecs(text string) ([]string, error) {
+	if _, err := parse.Parse("<pack>", text); err != nil {
+		return nil, err
+	}
+	return tokenUses(text), nil
+}
+
+// tokenUses scans text for the bare word "use" outside of any string or
+// comment, followed by whitespace and a spec (a run of non-whitespace,
+// non-terminator bytes), and returns every spec found, in order.
+func tokenUses(text string) []string {
+	var specs []string
+	inSingle, inDouble := false, false
+	i := 0
+	for i < len(text) {
+		c := text[i]
+		switch {
+		case inSingle:
+			if c == '\'' {
+				inSingle = false
+			}
+			i++
+			continue
+		case inDouble:
+			if c == '\\' && i+1 < len(text) {
+				i += 2
+				continue
+			}
+			if c == '"' {
+				inDouble = false
+			}
+			i++
+			continue
+		case c == '\'':
+			inSingle = true
+			i++
+			continue
+		case c == '"':
+			inDouble = true
+			i++
+			continue
+		case c == '#':
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+			continue
+		}
+
+		if isWordBoundary(text, i) && i+3 <= len(text) && text[i:i+3] == "use" &&
+			(i+3 == len(text) || isSpace(text[i+3])) {
+			j := i + 3
+			for j < len(text) && isSpace(text[j]) {
+				j++
+			}
+			start := j
+			for j < len(text) && !isSpace(text[j]) && text[j] != ';' && text[j] != '}' {
+				j++
+			}
+			if j > start {
+				specs = append(specs, text[start:j])
+			}
+			i = j
+			continue
+		}
+		i++
+	}
+	return specs
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// isWordBoundary reports whether the byte immediately before i (if any)
+// cannot be part of the same bareword as what starts at i, so that e.g.
+// "disuse foo" isn't mistaken for a use of "foo".
+func isWordBoundary(text string, i int) bool {
+	if i == 0 {
+		return true
+	}
+	c := text[i-1]
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == ':', c == '_':
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,39 @@
+package archive
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenUses(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"simple", "use a:b:c:d; put $a:b:c:d:name", []string{"a:b:c:d"}},
+		{"multiple", "use a; use b\nput $a:name $b:name", []string{"a", "b"}},
+		{"no uses", "put foo bar", nil},
+		{
+			"comment is not a use",
+			"# use fake\nuse real",
+			[]string{"real"},
+		},
+		{
+			"string literal is not a use",
+			`put "some use case"; use real`,
+			[]string{"real"},
+		},
+		{
+			"bareword containing use is not a use",
+			"disuse foo; use real",
+			[]string{"real"},
+		},
+	}
+	for _, tt := range tests {
+		got := tokenUses(tt.text)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: tokenUses(%q) = %v, want %v", tt.name, tt.text, got, tt.want)
+		}
+	}
+}